@@ -2,27 +2,53 @@ package apm
 
 import (
 	"context"
-	"fmt"
-	"strings"
+	"sync"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
+
+	"github.com/severgroup-tt/goredis/apm/internal/tags"
 )
 
-type opentracingHook struct{ tracer opentracing.Tracer }
+// wrapped tracks which (client, hook kind) pairs already have their hooks
+// registered, so that calling Wrap/WrapOTel/WrapWithMetrics more than once
+// on the same client (or WithContext repeatedly) doesn't accumulate
+// duplicate hooks and doesn't emit N spans per command after N calls.
+// Keying by kind lets independent instrumentation (tracing, metrics, otel)
+// be added to the same client without one registration blocking another.
+var wrapped sync.Map // map[wrapKey]struct{}
+
+type wrapKey struct {
+	client interface{}
+	kind   string
+}
+
+// markWrapped reports whether (client, kind) was not previously marked, and
+// marks it as wrapped as a side effect.
+func markWrapped(client interface{}, kind string) bool {
+	_, alreadyWrapped := wrapped.LoadOrStore(wrapKey{client, kind}, struct{}{})
+	return !alreadyWrapped
+}
+
+type opentracingHook struct {
+	tracer  opentracing.Tracer
+	opts    options
+	dbIndex int
+}
 
 var _ redis.Hook = opentracingHook{}
 
 func (h opentracingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
-	spanName := strings.ToUpper(cmd.Name())
+	spanName := tags.CmdName(cmd.Name())
 	span, _ := opentracing.StartSpanFromContextWithTracer(ctx, h.tracer, spanName)
 
-	ext.DBType.Set(span, "redis")
+	ext.DBType.Set(span, tags.DBSystemRedis)
 	ext.SpanKindRPCClient.Set(span)
-	ext.DBStatement.Set(span, fmt.Sprintf("%v", cmd.Args()))
+	ext.DBStatement.Set(span, formatStatement(cmd, h.opts))
 	// to maintain compatibility with opentelemetry convention
-	span.SetTag("db.system", "redis")
+	span.SetTag(tags.DBSystem, tags.DBSystemRedis)
+	span.SetTag(tags.DBRedisDBIndex, h.dbIndex)
 
 	ctx = opentracing.ContextWithSpan(ctx, span)
 
@@ -36,14 +62,15 @@ func (h opentracingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) erro
 }
 
 func (h opentracingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
-	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, h.tracer, "(pipeline)")
-	dbMethod := formatCommandsAsDbMethods(cmds)
+	span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, h.tracer, tags.PipelineSpanName)
+	dbMethod := formatPipelineStatement(cmds, h.opts)
 
-	ext.DBType.Set(span, "redis")
+	ext.DBType.Set(span, tags.DBSystemRedis)
 	ext.SpanKindRPCClient.Set(span)
-	ext.DBStatement.Set(span, fmt.Sprintf("%v", dbMethod))
+	ext.DBStatement.Set(span, dbMethod)
 	// to maintain compatibility with opentelemetry convention
-	span.SetTag("db.system", "redis")
+	span.SetTag(tags.DBSystem, tags.DBSystemRedis)
+	span.SetTag(tags.DBRedisDBIndex, h.dbIndex)
 
 	ctx = opentracing.ContextWithSpan(ctx, span)
 	return ctx, nil
@@ -69,9 +96,10 @@ type Client interface {
 	// or nil if a non-ring client is wrapped.
 	RingClient() *redis.Ring
 
-	// WithContext returns a shallow copy of the client with
-	// its context changed to ctx and will add instrumentation
-	// with client.WrapProcess and client.WrapProcessPipeline
+	// WithContext returns a shallow copy of the client with its context
+	// changed to ctx. Instrumentation is registered once, at Wrap time;
+	// WithContext does not add hooks, so it may be called any number of
+	// times on the same client without accumulating spans.
 	//
 	// To report commands as spans, ctx must contain a transaction or span.
 	WithContext(ctx context.Context) Client
@@ -80,18 +108,43 @@ type Client interface {
 // Wrap wraps client such that executed commands are reported as spans to Elastic APM,
 // using the client's associated context.
 // A context-specific client may be obtained by using Client.WithContext.
-func Wrap(client redis.UniversalClient, tracer opentracing.Tracer) Client {
+//
+// By default the db.statement tag is the full, untruncated cmd.Args() of
+// each command other than AUTH, HELLO and MIGRATE, which are always
+// redacted; pass WithStatementFormatter(RedactValues()) and/or
+// WithMaxStatementLen to avoid leaking values or emitting oversized tags.
+//
+// Hooks are registered on the underlying client exactly once: calling Wrap
+// more than once on the same client is a no-op past the first call.
+func Wrap(client redis.UniversalClient, tracer opentracing.Tracer, opts ...Option) Client {
 	if tracer == nil {
 		tracer = opentracing.GlobalTracer()
 	}
 
-	switch client.(type) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	switch c := client.(type) {
 	case *redis.Client:
-		return contextClient{Client: client.(*redis.Client), tracer: tracer}
+		if markWrapped(c, "opentracing") {
+			c.AddHook(opentracingHook{tracer, o, c.Options().DB})
+		}
+		return contextClient{Client: c, tracer: tracer, opts: o}
 	case *redis.ClusterClient:
-		return contextClusterClient{ClusterClient: client.(*redis.ClusterClient), tracer: tracer}
+		if markWrapped(c, "opentracing") {
+			// Cluster clients don't support SELECT; every node stays on db 0.
+			c.AddHook(opentracingHook{tracer, o, 0})
+			c.AddHook(clusterHook{cluster: c, cache: &clusterSlotCache{}})
+		}
+		return contextClusterClient{ClusterClient: c, tracer: tracer, opts: o}
 	case *redis.Ring:
-		return contextRingClient{Ring: client.(*redis.Ring), tracer: tracer}
+		if markWrapped(c, "opentracing") {
+			c.AddHook(opentracingHook{tracer, o, 0})
+			c.AddHook(ringHook{ring: c})
+		}
+		return contextRingClient{Ring: c, tracer: tracer, opts: o}
 	}
 
 	return client.(Client)
@@ -100,13 +153,11 @@ func Wrap(client redis.UniversalClient, tracer opentracing.Tracer) Client {
 type contextClient struct {
 	*redis.Client
 	tracer opentracing.Tracer
+	opts   options
 }
 
 func (c contextClient) WithContext(ctx context.Context) Client {
 	c.Client = c.Client.WithContext(ctx)
-
-	c.AddHook(opentracingHook{c.tracer})
-
 	return c
 }
 
@@ -121,6 +172,7 @@ func (c contextClient) RingClient() *redis.Ring {
 type contextClusterClient struct {
 	*redis.ClusterClient
 	tracer opentracing.Tracer
+	opts   options
 }
 
 func (c contextClusterClient) Cluster() *redis.ClusterClient {
@@ -133,15 +185,13 @@ func (c contextClusterClient) RingClient() *redis.Ring {
 
 func (c contextClusterClient) WithContext(ctx context.Context) Client {
 	c.ClusterClient = c.ClusterClient.WithContext(ctx)
-
-	c.AddHook(opentracingHook{c.tracer})
-
 	return c
 }
 
 type contextRingClient struct {
 	*redis.Ring
 	tracer opentracing.Tracer
+	opts   options
 }
 
 func (c contextRingClient) Cluster() *redis.ClusterClient {
@@ -155,17 +205,13 @@ func (c contextRingClient) RingClient() *redis.Ring {
 
 func (c contextRingClient) WithContext(ctx context.Context) Client {
 	c.Ring = c.Ring.WithContext(ctx)
-
-	c.AddHook(opentracingHook{c.tracer})
-
 	return c
 }
 
 func formatCommandsAsDbMethods(cmds []redis.Cmder) string {
 	cmdsAsDbMethods := make([]string, len(cmds))
 	for i, cmd := range cmds {
-		dbMethod := cmd.Name()
-		cmdsAsDbMethods[i] = dbMethod
+		cmdsAsDbMethods[i] = cmd.Name()
 	}
-	return strings.Join(cmdsAsDbMethods, " -> ")
+	return tags.PipelineStatement(cmdsAsDbMethods)
 }
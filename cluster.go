@@ -0,0 +1,203 @@
+package apm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/dgryski/go-rendezvous"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/severgroup-tt/goredis/apm/internal/crc16"
+	"github.com/severgroup-tt/goredis/apm/internal/tags"
+)
+
+type clusterSlotKey struct{}
+
+type clusterSlotRefreshKey struct{}
+
+// clusterSlotCache memoizes ClusterSlots() so clusterHook doesn't issue an
+// extra round trip for every command it tags.
+type clusterSlotCache struct {
+	mu        sync.Mutex
+	slots     []redis.ClusterSlot
+	expiresAt time.Time
+}
+
+const clusterSlotCacheTTL = time.Minute
+
+func (c *clusterSlotCache) addrForSlot(ctx context.Context, cc *redis.ClusterClient, slot int) (string, bool) {
+	// The refresh below runs CLUSTER SLOTS through cc's own hooks, which call
+	// back in here; that nested lookup is skipped instead of re-locking c.mu.
+	if ctx.Value(clusterSlotRefreshKey{}) != nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Now().After(c.expiresAt) {
+		if slots, err := cc.ClusterSlots(context.WithValue(ctx, clusterSlotRefreshKey{}, true)).Result(); err == nil {
+			c.slots = slots
+			c.expiresAt = time.Now().Add(clusterSlotCacheTTL)
+		}
+	}
+
+	for _, s := range c.slots {
+		if slot >= s.Start && slot <= s.End && len(s.Nodes) > 0 {
+			return s.Nodes[0].Addr, true
+		}
+	}
+
+	return "", false
+}
+
+// clusterHook adds cluster-specific tags to the span started by
+// opentracingHook: the hashed slot for the command's key, the node address
+// that actually served the request, and whether the command was retried
+// after a MOVED/ASK redirection.
+//
+// It is registered alongside opentracingHook (BeforeProcess runs in
+// registration order), so by the time it runs there is already a span in
+// ctx to tag.
+//
+// Known limitation: ClusterClient.Process wraps its entire internal
+// MOVED/ASK retry loop, so cmd.Err() here is nil whenever a redirect was
+// followed and the command went on to succeed -- db.redis.retried only
+// becomes true once MaxRedirects attempts are exhausted and the command
+// fails outright. Surfacing the common "redirected once then succeeded"
+// case would require a hook on each per-node *redis.Client, but go-redis
+// v8's ClusterClient has no way to observe nodes as they're created
+// (unlike v9's OnNewNode), so that isn't available here.
+type clusterHook struct {
+	cluster *redis.ClusterClient
+	cache   *clusterSlotCache
+}
+
+var _ redis.Hook = clusterHook{}
+
+func (h clusterHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	slot := crc16.HashSlot(firstKeyArg(cmd))
+	ctx = context.WithValue(ctx, clusterSlotKey{}, slot)
+
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span.SetTag(tags.DBRedisSlot, slot)
+	}
+
+	return ctx, nil
+}
+
+func (h clusterHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+
+	if slot, ok := ctx.Value(clusterSlotKey{}).(int); ok {
+		if addr, ok := h.cache.addrForSlot(ctx, h.cluster, slot); ok {
+			host, port := tags.SplitHostPort(addr)
+			span.SetTag(tags.NetPeerName, host)
+			span.SetTag(tags.NetPeerPort, port)
+		}
+	}
+
+	span.SetTag(tags.DBRedisRetried, isRedirectErr(cmd.Err()))
+
+	return nil
+}
+
+func (h clusterHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h clusterHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+
+	retried := false
+	for _, cmd := range cmds {
+		if isRedirectErr(cmd.Err()) {
+			retried = true
+			break
+		}
+	}
+	span.SetTag(tags.DBRedisRetried, retried)
+
+	return nil
+}
+
+func firstKeyArg(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return ""
+	}
+	return fmt.Sprintf("%v", args[1])
+}
+
+func isRedirectErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.HasPrefix(msg, "MOVED ") || strings.HasPrefix(msg, "ASK ")
+}
+
+// ringHook tags spans with the consistent-hash shard name that served the
+// request, mirroring clusterHook for redis.Ring.
+type ringHook struct{ ring *redis.Ring }
+
+var _ redis.Hook = ringHook{}
+
+func (h ringHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		if shard, ok := ringShardForKey(h.ring, firstKeyArg(cmd)); ok {
+			span.SetTag(tags.DBRedisShard, shard)
+		}
+	}
+	return ctx, nil
+}
+
+func (h ringHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h ringHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h ringHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+// ringShardForKey picks the shard that would serve key using the same
+// rendezvous (HRW) hashing over xxhash that Ring's default ConsistentHash
+// (NewRendezvous) uses, hashing only the {tag} part of key when it has one.
+// It hashes over every shard in RingOptions.Addrs, whether or not the shard
+// is currently up.
+func ringShardForKey(ring *redis.Ring, key string) (string, bool) {
+	names := ringShardNames(ring)
+	if len(names) == 0 {
+		return "", false
+	}
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return rendezvous.New(names, xxhash.Sum64String).Lookup(key), true
+}
+
+func ringShardNames(ring *redis.Ring) []string {
+	opts := ring.Options()
+	names := make([]string, 0, len(opts.Addrs))
+	for name := range opts.Addrs {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,53 @@
+// Package tags holds the span/metric tag-setting logic shared by the v8 and
+// v9 apm packages, so that upgrading the wrapped go-redis version doesn't
+// require re-deriving how commands are named and rendered.
+package tags
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DB tag keys, shared between the OpenTracing and OpenTelemetry hooks and
+// kept in sync with the semantic conventions used by the ecosystem's
+// redisotel integration.
+const (
+	DBSystem         = "db.system"
+	DBStatement      = "db.statement"
+	DBRedisDBIndex   = "db.redis.database_index"
+	DBRedisNumCmd    = "db.redis.num_cmd"
+	DBRedisSlot      = "db.redis.slot"
+	DBRedisRetried   = "db.redis.retried"
+	DBRedisShard     = "db.redis.shard"
+	NetPeerName      = "net.peer.name"
+	NetPeerPort      = "net.peer.port"
+	DBSystemRedis    = "redis"
+	PipelineSpanName = "(pipeline)"
+)
+
+// CmdName upper-cases a command name for use as a span name or metric label.
+func CmdName(name string) string {
+	return strings.ToUpper(name)
+}
+
+// StatementFromArgs renders a command's arguments the same way across
+// versions: the raw Args() slice formatted with %v.
+func StatementFromArgs(args []interface{}) string {
+	return fmt.Sprintf("%v", args)
+}
+
+// PipelineStatement joins a pipeline's command names into a single
+// db.statement value.
+func PipelineStatement(cmdNames []string) string {
+	return strings.Join(cmdNames, " -> ")
+}
+
+// SplitHostPort splits a "host:port" address into its net.peer.name and
+// net.peer.port tag values. It returns addr, "" if no port is present.
+func SplitHostPort(addr string) (host, port string) {
+	i := strings.LastIndex(addr, ":")
+	if i < 0 {
+		return addr, ""
+	}
+	return addr[:i], addr[i+1:]
+}
@@ -0,0 +1,220 @@
+package apm
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "goredis"
+
+type metricsHook struct {
+	latency *prometheus.HistogramVec
+	errors  *prometheus.CounterVec
+}
+
+var _ redis.Hook = (*metricsHook)(nil)
+
+type startedAtKey struct{}
+
+func newMetricsHook(reg prometheus.Registerer) *metricsHook {
+	h := &metricsHook{
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "redis",
+			Name:      "command_duration_seconds",
+			Help:      "Duration of redis commands, by command name.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"cmd"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "redis",
+			Name:      "command_errors_total",
+			Help:      "Count of redis command errors, by command name and error class.",
+		}, []string{"cmd", "class"}),
+	}
+
+	reg.MustRegister(h.latency, h.errors)
+
+	return h
+}
+
+func (h *metricsHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startedAtKey{}, time.Now()), nil
+}
+
+func (h *metricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.observe(ctx, cmd.Name(), cmd.Err())
+	return nil
+}
+
+func (h *metricsHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startedAtKey{}, time.Now()), nil
+}
+
+func (h *metricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	for _, cmd := range cmds {
+		h.observe(ctx, cmd.Name(), cmd.Err())
+	}
+	return nil
+}
+
+func (h *metricsHook) observe(ctx context.Context, cmdName string, err error) {
+	cmdName = strings.ToUpper(cmdName)
+
+	if startedAt, ok := ctx.Value(startedAtKey{}).(time.Time); ok {
+		h.latency.WithLabelValues(cmdName).Observe(time.Since(startedAt).Seconds())
+	}
+
+	if class := errorClass(err); class != "" {
+		h.errors.WithLabelValues(cmdName, class).Inc()
+	}
+}
+
+func errorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case err == redis.Nil:
+		return "nil"
+	case err == context.Canceled, err == context.DeadlineExceeded:
+		return "context"
+	default:
+		return "error"
+	}
+}
+
+type poolStatsGetter interface {
+	PoolStats() *redis.PoolStats
+}
+
+// poolGauges holds the pool-stats GaugeVecs shared by every collector
+// registered for a single WrapWithMetrics call. They're registered exactly
+// once and then reused per shard via the "node" label, since Prometheus
+// rejects registering two collectors under the same fully-qualified name.
+type poolGauges struct {
+	hits, misses, timeouts            *prometheus.GaugeVec
+	totalConns, idleConns, staleConns *prometheus.GaugeVec
+}
+
+func newPoolGauges(reg prometheus.Registerer) *poolGauges {
+	g := &poolGauges{
+		hits:       newPoolGauge("hits", "Number of times a free connection was found in the pool."),
+		misses:     newPoolGauge("misses", "Number of times a free connection was not found in the pool."),
+		timeouts:   newPoolGauge("timeouts", "Number of times a wait timeout occurred."),
+		totalConns: newPoolGauge("total_conns", "Number of total connections in the pool."),
+		idleConns:  newPoolGauge("idle_conns", "Number of idle connections in the pool."),
+		staleConns: newPoolGauge("stale_conns", "Number of stale connections removed from the pool."),
+	}
+
+	reg.MustRegister(g.hits, g.misses, g.timeouts, g.totalConns, g.idleConns, g.staleConns)
+
+	return g
+}
+
+func newPoolGauge(name, help string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "redis_pool",
+		Name:      name,
+		Help:      help,
+	}, []string{"node"})
+}
+
+// poolStatsCollector periodically exports PoolStats() as Prometheus gauges
+// for a single redis client or one shard of a cluster/ring, via the shared
+// poolGauges for its WrapWithMetrics call.
+type poolStatsCollector struct {
+	getter poolStatsGetter
+	node   string
+	gauges *poolGauges
+}
+
+func (c *poolStatsCollector) collect() {
+	stats := c.getter.PoolStats()
+	c.gauges.hits.WithLabelValues(c.node).Set(float64(stats.Hits))
+	c.gauges.misses.WithLabelValues(c.node).Set(float64(stats.Misses))
+	c.gauges.timeouts.WithLabelValues(c.node).Set(float64(stats.Timeouts))
+	c.gauges.totalConns.WithLabelValues(c.node).Set(float64(stats.TotalConns))
+	c.gauges.idleConns.WithLabelValues(c.node).Set(float64(stats.IdleConns))
+	c.gauges.staleConns.WithLabelValues(c.node).Set(float64(stats.StaleConns))
+}
+
+// pollPoolStats scrapes collectors on an interval until ctx is done.
+func pollPoolStats(ctx context.Context, interval time.Duration, collectors ...*poolStatsCollector) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, c := range collectors {
+				c.collect()
+			}
+		}
+	}
+}
+
+const defaultPoolStatsInterval = 15 * time.Second
+
+// WrapWithMetrics wraps client such that executed commands are instrumented
+// with Prometheus histograms (latency) and counters (errors by command name
+// and error class), and registers a background collector that periodically
+// exports PoolStats() as gauges. It composes with Wrap/WrapOTel: callers
+// may add tracing and metrics hooks independently on the same client.
+//
+// Cluster and ring clients get one pool-stats collector per shard, labeled
+// with the shard's node address / name.
+func WrapWithMetrics(client redis.UniversalClient, reg prometheus.Registerer) redis.UniversalClient {
+	if !markWrapped(client, "metrics") {
+		return client
+	}
+
+	hook := newMetricsHook(reg)
+	client.AddHook(hook)
+
+	gauges := newPoolGauges(reg)
+
+	var collectors []*poolStatsCollector
+
+	switch c := client.(type) {
+	case *redis.ClusterClient:
+		collectors = append(collectors, &poolStatsCollector{getter: c, node: "cluster", gauges: gauges})
+
+		_ = c.ForEachShard(context.Background(), func(ctx context.Context, shard *redis.Client) error {
+			collectors = append(collectors, &poolStatsCollector{getter: shard, node: shard.Options().Addr, gauges: gauges})
+			return nil
+		})
+	case *redis.Ring:
+		shardNames := ringShardNodeNames(c)
+
+		_ = c.ForEachShard(context.Background(), func(ctx context.Context, shard *redis.Client) error {
+			node := shardNames[shard.Options().Addr]
+			collectors = append(collectors, &poolStatsCollector{getter: shard, node: node, gauges: gauges})
+			return nil
+		})
+	case *redis.Client:
+		collectors = append(collectors, &poolStatsCollector{getter: c, node: c.Options().Addr, gauges: gauges})
+	}
+
+	go pollPoolStats(context.Background(), defaultPoolStatsInterval, collectors...)
+
+	return client
+}
+
+// ringShardNodeNames maps each shard's address back to its configured name,
+// so per-shard pool-stats collectors can be labeled the same way the user
+// configured the ring (RingOptions.Addrs is name -> addr).
+func ringShardNodeNames(ring *redis.Ring) map[string]string {
+	addrs := ring.Options().Addrs
+	names := make(map[string]string, len(addrs))
+	for name, addr := range addrs {
+		names[addr] = name
+	}
+	return names
+}
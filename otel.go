@@ -0,0 +1,183 @@
+package apm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/severgroup-tt/goredis/apm/internal/tags"
+)
+
+type otelHook struct {
+	tracer  trace.Tracer
+	addr    string
+	dbIndex int
+	opts    options
+}
+
+var _ redis.Hook = otelHook{}
+
+func (h otelHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx, nil
+	}
+
+	ctx, span := h.tracer.Start(ctx, tags.CmdName(cmd.Name()), trace.WithSpanKind(trace.SpanKindClient))
+	span.SetAttributes(
+		attribute.String(tags.DBSystem, tags.DBSystemRedis),
+		attribute.String(tags.DBStatement, formatStatement(cmd, h.opts)),
+		attribute.Int(tags.DBRedisDBIndex, h.dbIndex),
+	)
+	h.setPeerAttributes(span)
+
+	return ctx, nil
+}
+
+func (h otelHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	span := trace.SpanFromContext(ctx)
+	recordCmdError(span, cmd.Err())
+	span.End()
+	return nil
+}
+
+func (h otelHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx, nil
+	}
+
+	statement := formatPipelineStatement(cmds, h.opts)
+	ctx, span := h.tracer.Start(ctx, fmt.Sprintf("pipeline %s", statement), trace.WithSpanKind(trace.SpanKindClient))
+	span.SetAttributes(
+		attribute.String(tags.DBSystem, tags.DBSystemRedis),
+		attribute.String(tags.DBStatement, statement),
+		attribute.Int(tags.DBRedisNumCmd, len(cmds)),
+		attribute.Int(tags.DBRedisDBIndex, h.dbIndex),
+	)
+	h.setPeerAttributes(span)
+
+	return ctx, nil
+}
+
+func (h otelHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	span := trace.SpanFromContext(ctx)
+	for _, cmd := range cmds {
+		if cmd.Err() != nil && cmd.Err() != redis.Nil {
+			recordCmdError(span, cmd.Err())
+			break
+		}
+	}
+	span.End()
+	return nil
+}
+
+func (h otelHook) setPeerAttributes(span trace.Span) {
+	if h.addr == "" {
+		return
+	}
+	host, port := tags.SplitHostPort(h.addr)
+	span.SetAttributes(
+		attribute.String(tags.NetPeerName, host),
+		attribute.String(tags.NetPeerPort, port),
+	)
+}
+
+func recordCmdError(span trace.Span, err error) {
+	if err == nil || err == redis.Nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// otelClient, otelClusterClient and otelRingClient mirror the
+// contextClient family but instrument via OpenTelemetry instead of
+// OpenTracing, so WrapOTel can be used independently of Wrap.
+
+type otelClient struct {
+	*redis.Client
+	tracer trace.Tracer
+}
+
+func (c otelClient) Cluster() *redis.ClusterClient { return nil }
+func (c otelClient) RingClient() *redis.Ring       { return nil }
+
+func (c otelClient) WithContext(ctx context.Context) Client {
+	c.Client = c.Client.WithContext(ctx)
+	return c
+}
+
+type otelClusterClient struct {
+	*redis.ClusterClient
+	tracer trace.Tracer
+}
+
+func (c otelClusterClient) Cluster() *redis.ClusterClient { return c.ClusterClient }
+func (c otelClusterClient) RingClient() *redis.Ring       { return nil }
+
+func (c otelClusterClient) WithContext(ctx context.Context) Client {
+	c.ClusterClient = c.ClusterClient.WithContext(ctx)
+	return c
+}
+
+type otelRingClient struct {
+	*redis.Ring
+	tracer trace.Tracer
+}
+
+func (c otelRingClient) Cluster() *redis.ClusterClient { return nil }
+func (c otelRingClient) RingClient() *redis.Ring       { return c.Ring }
+
+func (c otelRingClient) WithContext(ctx context.Context) Client {
+	c.Ring = c.Ring.WithContext(ctx)
+	return c
+}
+
+// WrapOTel wraps client such that executed commands are reported as spans
+// to an OpenTelemetry TracerProvider, using the client's associated context.
+// A context-specific client may be obtained by using Client.WithContext.
+//
+// WrapOTel mirrors Wrap and may be used independently of it; span creation
+// is skipped whenever the incoming context carries no recording span.
+// Like Wrap, it registers its hook on the underlying client exactly once.
+//
+// By default the db.statement attribute is the full, untruncated cmd.Args()
+// of each command other than AUTH, HELLO and MIGRATE, which are always
+// redacted; pass WithStatementFormatter(RedactValues()) and/or
+// WithMaxStatementLen to avoid leaking values or emitting oversized attributes.
+func WrapOTel(client redis.UniversalClient, tp trace.TracerProvider, opts ...Option) Client {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	tracer := tp.Tracer("github.com/severgroup-tt/goredis/apm")
+
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	switch c := client.(type) {
+	case *redis.Client:
+		if markWrapped(c, "otel") {
+			c.AddHook(otelHook{tracer: tracer, addr: c.Options().Addr, dbIndex: c.Options().DB, opts: o})
+		}
+		return otelClient{Client: c, tracer: tracer}
+	case *redis.ClusterClient:
+		if markWrapped(c, "otel") {
+			// Cluster clients don't support SELECT; every node stays on db 0.
+			c.AddHook(otelHook{tracer: tracer, opts: o})
+		}
+		return otelClusterClient{ClusterClient: c, tracer: tracer}
+	case *redis.Ring:
+		if markWrapped(c, "otel") {
+			c.AddHook(otelHook{tracer: tracer, opts: o})
+		}
+		return otelRingClient{Ring: c, tracer: tracer}
+	}
+
+	return client.(Client)
+}
@@ -0,0 +1,101 @@
+package apm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/severgroup-tt/goredis/apm/internal/tags"
+)
+
+// sensitiveCommands are always redacted in full, regardless of the
+// configured StatementFormatter, because their arguments can contain
+// credentials.
+var sensitiveCommands = map[string]bool{
+	"AUTH":    true,
+	"HELLO":   true,
+	"MIGRATE": true,
+}
+
+// options configures how Wrap renders the db.statement tag.
+type options struct {
+	formatter func(redis.Cmder) string
+	maxLen    int
+}
+
+func defaultOptions() options {
+	return options{formatter: defaultStatementFormatter}
+}
+
+// Option configures Wrap. See WithStatementFormatter, WithMaxStatementLen
+// and RedactValues.
+type Option func(*options)
+
+// WithStatementFormatter overrides how a command is rendered into the
+// db.statement tag. The default formatter writes the full cmd.Args() slice,
+// which is convenient for local debugging but can leak values (e.g. SET,
+// HSET) and produce very large tags on pipelines; use RedactValues for a
+// safer default.
+func WithStatementFormatter(f func(redis.Cmder) string) Option {
+	return func(o *options) { o.formatter = f }
+}
+
+// WithMaxStatementLen truncates the rendered db.statement tag to n bytes.
+// A value of 0 (the default) disables truncation.
+func WithMaxStatementLen(n int) Option {
+	return func(o *options) { o.maxLen = n }
+}
+
+// RedactValues returns a StatementFormatter that emits only the command verb
+// plus its first key argument, replacing the rest with "?" placeholders, e.g.
+// SET user:42 ? instead of [set user:42 some-secret-value].
+func RedactValues() func(redis.Cmder) string {
+	return func(cmd redis.Cmder) string {
+		args := cmd.Args()
+		verb := tags.CmdName(cmd.Name())
+		if len(args) <= 1 {
+			return verb
+		}
+
+		parts := make([]string, 0, len(args)-1)
+		parts = append(parts, verb, fmt.Sprintf("%v", args[1]))
+		for range args[2:] {
+			parts = append(parts, "?")
+		}
+		return strings.Join(parts, " ")
+	}
+}
+
+func defaultStatementFormatter(cmd redis.Cmder) string {
+	return tags.StatementFromArgs(cmd.Args())
+}
+
+// formatStatement renders cmd's db.statement tag, applying the sensitive
+// command redaction list and the configured formatter/length limit.
+func formatStatement(cmd redis.Cmder, o options) string {
+	name := tags.CmdName(cmd.Name())
+	if sensitiveCommands[name] {
+		return name + " ***"
+	}
+
+	formatter := o.formatter
+	if formatter == nil {
+		formatter = defaultStatementFormatter
+	}
+
+	return truncate(formatter(cmd), o.maxLen)
+}
+
+// formatPipelineStatement renders a pipeline's db.statement tag from its
+// per-command names, applying the configured length limit.
+func formatPipelineStatement(cmds []redis.Cmder, o options) string {
+	return truncate(formatCommandsAsDbMethods(cmds), o.maxLen)
+}
+
+func truncate(s string, maxLen int) string {
+	if maxLen > 0 && len(s) > maxLen {
+		return s[:maxLen]
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+// Package v9 mirrors the root apm package on top of
+// github.com/redis/go-redis/v9, whose renamed module path and hook
+// signature (ProcessHook/ProcessPipelineHook closures instead of
+// Before/After pairs) make it incompatible with the v8 Hook interface.
+//
+// v9 also dropped *redis.Client.WithContext et al.: every command already
+// takes ctx as its first argument, so there is no per-request client to
+// thread a transaction/span through and no wrapper type to keep in sync
+// with v8's Client interface. Wrap registers a hook once and hands the
+// client straight back.
+package v9
+
+import (
+	"context"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/severgroup-tt/goredis/apm/internal/tags"
+)
+
+// wrapped tracks which clients already have their hook registered, so that
+// calling Wrap more than once on the same client doesn't accumulate
+// duplicate hooks and doesn't emit N spans per command after N calls.
+var wrapped sync.Map // map[redis.UniversalClient]struct{}
+
+func markWrapped(client redis.UniversalClient) bool {
+	_, alreadyWrapped := wrapped.LoadOrStore(client, struct{}{})
+	return !alreadyWrapped
+}
+
+type hookImpl struct {
+	tracer  opentracing.Tracer
+	dbIndex int
+}
+
+var _ redis.Hook = hookImpl{}
+
+func (h hookImpl) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h hookImpl) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, h.tracer, tags.CmdName(cmd.Name()))
+		defer span.Finish()
+
+		ext.DBType.Set(span, tags.DBSystemRedis)
+		ext.SpanKindRPCClient.Set(span)
+		ext.DBStatement.Set(span, tags.StatementFromArgs(cmd.Args()))
+		// to maintain compatibility with opentelemetry convention
+		span.SetTag(tags.DBSystem, tags.DBSystemRedis)
+		span.SetTag(tags.DBRedisDBIndex, h.dbIndex)
+
+		return next(ctx, cmd)
+	}
+}
+
+func (h hookImpl) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		span, ctx := opentracing.StartSpanFromContextWithTracer(ctx, h.tracer, tags.PipelineSpanName)
+		defer span.Finish()
+
+		cmdNames := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			cmdNames[i] = cmd.Name()
+		}
+
+		ext.DBType.Set(span, tags.DBSystemRedis)
+		ext.SpanKindRPCClient.Set(span)
+		ext.DBStatement.Set(span, tags.PipelineStatement(cmdNames))
+		// to maintain compatibility with opentelemetry convention
+		span.SetTag(tags.DBSystem, tags.DBSystemRedis)
+		span.SetTag(tags.DBRedisDBIndex, h.dbIndex)
+
+		return next(ctx, cmds)
+	}
+}
+
+// Wrap registers an OpenTracing redis.Hook on client so that every command
+// executed through it is reported as a span to Elastic APM, using whatever
+// ctx the caller passes to that command. Wrap is idempotent: calling it
+// more than once on the same client registers the hook only once.
+func Wrap(client redis.UniversalClient, tracer opentracing.Tracer) redis.UniversalClient {
+	if tracer == nil {
+		tracer = opentracing.GlobalTracer()
+	}
+
+	if markWrapped(client) {
+		client.AddHook(hookImpl{tracer: tracer, dbIndex: dbIndexOf(client)})
+	}
+
+	return client
+}
+
+// dbIndexOf returns the selected DB index for a plain *redis.Client. Cluster
+// and Ring clients don't support SELECT, so every node stays on db 0.
+func dbIndexOf(client redis.UniversalClient) int {
+	if c, ok := client.(*redis.Client); ok {
+		return c.Options().DB
+	}
+	return 0
+}
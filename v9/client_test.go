@@ -0,0 +1,62 @@
+package v9
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/redis/go-redis/v9"
+)
+
+func countPingSpans(tracer *mocktracer.MockTracer) int {
+	n := 0
+	for _, s := range tracer.FinishedSpans() {
+		if s.OperationName == "PING" {
+			n++
+		}
+	}
+	return n
+}
+
+// TestWrapEmitsOneSpanPerCommand mirrors the v8 package's regression test:
+// since v9 commands take ctx directly (no WithContext), the hook must be
+// registered exactly once by Wrap, not once per command.
+func TestWrapEmitsOneSpanPerCommand(t *testing.T) {
+	tracer := mocktracer.New()
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	wrapped := Wrap(client, tracer)
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		span := tracer.StartSpan("root")
+		ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+		_ = wrapped.Ping(ctx)
+
+		span.Finish()
+	}
+
+	if got := countPingSpans(tracer); got != n {
+		t.Fatalf("expected %d PING spans, got %d", n, got)
+	}
+}
+
+// TestWrapIsIdempotent asserts that wrapping the same client twice does not
+// register the hook twice.
+func TestWrapIsIdempotent(t *testing.T) {
+	tracer := mocktracer.New()
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+
+	Wrap(client, tracer)
+	wrapped := Wrap(client, tracer)
+
+	span := tracer.StartSpan("root")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	_ = wrapped.Ping(ctx)
+	span.Finish()
+
+	if got := countPingSpans(tracer); got != 1 {
+		t.Fatalf("expected exactly 1 PING span after double Wrap, got %d", got)
+	}
+}